@@ -0,0 +1,176 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package config defines the schema of containerd's configuration file and
+// the types passed down to the server package's setup routines.
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config is the root of containerd's configuration file.
+type Config struct {
+	// Version of the config file.
+	Version int `toml:"version"`
+	// Root is the path to a directory where containerd will store
+	// persistent data.
+	Root string `toml:"root"`
+	// State is the path to a directory where containerd will store
+	// transient data.
+	State string `toml:"state"`
+	// GRPC configures containerd's GRPC server.
+	GRPC GRPCConfig `toml:"grpc"`
+}
+
+// GRPCConfig configures the containerd GRPC server, including the TLS
+// credentials and peer authorization policy used by its TCP listener.
+type GRPCConfig struct {
+	// Address is the path of the containerd GRPC socket.
+	Address string `toml:"address"`
+	// TCPAddress is the host:port the GRPC server additionally listens on
+	// over TCP. TLS is required on this listener; all TCPTLS* fields below
+	// configure it.
+	TCPAddress string `toml:"tcp_address"`
+	// UID is the user id that owns the GRPC socket.
+	UID int `toml:"uid"`
+	// GID is the group id that owns the GRPC socket.
+	GID int `toml:"gid"`
+	// MaxRecvMsgSize is the maximum message size the server will receive.
+	MaxRecvMsgSize int `toml:"max_recv_message_size"`
+	// MaxSendMsgSize is the maximum message size the server will send.
+	MaxSendMsgSize int `toml:"max_send_message_size"`
+
+	// TCPTLSCName is the subject common name used to locate the TCP
+	// listener's TLS certificate when no TCPTLSProvider is configured
+	// (legacy behavior), and as the subject of a certificate enrolled via
+	// TCPTLSACMEEnroll.
+	TCPTLSCName string `toml:"tcp_tls_cname"`
+	// TCPTLSRefreshInterval is how often the TCP listener's TLS certificate
+	// is re-read from its backing store. Defaults to 10 minutes if zero.
+	TCPTLSRefreshInterval time.Duration `toml:"tcp_tls_refresh_interval"`
+
+	// TCPTLSCertMatchBy selects how TCPTLSCertMatch is interpreted when
+	// locating a certificate in the Windows Certificate Store: "subject"
+	// (the default), "issuer" or "thumbprint".
+	TCPTLSCertMatchBy string `toml:"tcp_tls_cert_match_by"`
+	// TCPTLSCertMatch is the value matched against according to
+	// TCPTLSCertMatchBy. Falls back to TCPTLSCName when unset.
+	TCPTLSCertMatch string `toml:"tcp_tls_cert_match"`
+	// TCPTLSCertSkipInvalid skips expired, not-yet-valid or wrong-EKU
+	// certificates in the Windows Certificate Store in favor of the next
+	// candidate matching TCPTLSCertMatchBy/TCPTLSCertMatch, instead of
+	// failing on the first match.
+	TCPTLSCertSkipInvalid bool `toml:"tcp_tls_cert_skip_invalid"`
+
+	// When more than one of the three allowlists below is set, a peer that
+	// matches an entry in any one of them is allowed.
+	//
+	// TCPTLSAllowedSPIFFEIDs, if non-empty, restricts TCP GRPC clients to
+	// peers presenting a verified certificate with one of these SPIFFE IDs
+	// as a URI SAN.
+	TCPTLSAllowedSPIFFEIDs []string `toml:"tcp_tls_allowed_spiffe_ids"`
+	// TCPTLSAllowedDNSNames, if non-empty, restricts TCP GRPC clients to
+	// peers presenting a verified certificate with one of these DNS SANs.
+	TCPTLSAllowedDNSNames []string `toml:"tcp_tls_allowed_dns_names"`
+	// TCPTLSAllowedOUs, if non-empty, restricts TCP GRPC clients to peers
+	// presenting a verified certificate with one of these Subject
+	// Organizational Units.
+	TCPTLSAllowedOUs []string `toml:"tcp_tls_allowed_ous"`
+
+	// TCPTLSACMEEnroll, when set, enrolls a certificate for TCPTLSCName from
+	// an ACME CA on startup if the Windows Certificate Store does not
+	// already have one, and keeps it renewed for the lifetime of the
+	// containerd process.
+	TCPTLSACMEEnroll bool `toml:"tcp_tls_acme_enroll"`
+	// TCPTLSACMEDirectoryURL is the ACME CA's directory URL. Required when
+	// TCPTLSACMEEnroll is set.
+	TCPTLSACMEDirectoryURL string `toml:"tcp_tls_acme_directory_url"`
+	// TCPTLSACMEChallengeType is the preferred ACME challenge type
+	// ("tls-alpn-01" or "http-01"). Defaults to "tls-alpn-01", falling back
+	// to "http-01" if the CA does not offer it.
+	TCPTLSACMEChallengeType string `toml:"tcp_tls_acme_challenge_type"`
+	// TCPTLSACMEChallengeResponder names the responder used to complete the
+	// selected challenge. "tls-alpn-01" and "http-01" are built in.
+	TCPTLSACMEChallengeResponder string `toml:"tcp_tls_acme_challenge_responder"`
+	// TCPTLSACMEHTTPAddress is the address the http-01 challenge responder
+	// listens on. Defaults to ":80".
+	TCPTLSACMEHTTPAddress string `toml:"tcp_tls_acme_http_address"`
+	// TCPTLSACMETLSALPNAddress is the address the tls-alpn-01 challenge
+	// responder listens on. Defaults to ":443". This must not collide with
+	// TCPAddress, or renewal will fail with "address already in use" while
+	// the TCP GRPC server is running.
+	TCPTLSACMETLSALPNAddress string `toml:"tcp_tls_acme_tls_alpn_address"`
+
+	// TCPTLSProvider selects a registered TLSCredentialProvider
+	// ("pkcs11", "macos-keychain", "tpm2", "windows-certstore") to source
+	// the TCP listener's TLS credentials from, instead of the legacy
+	// Windows Certificate Store lookup.
+	TCPTLSProvider string `toml:"tcp_tls_provider"`
+	// TCPTLSCAFile is an optional PEM bundle of client CAs to verify mTLS
+	// peers against, used by credential-provider-backed setups.
+	TCPTLSCAFile string `toml:"tcp_tls_ca_file"`
+
+	// TCPTLSPKCS11ModulePath is the path to the PKCS#11 module (.so/.dll)
+	// used by the "pkcs11" provider.
+	TCPTLSPKCS11ModulePath string `toml:"tcp_tls_pkcs11_module_path"`
+	// TCPTLSPKCS11TokenLabel identifies the token to open.
+	TCPTLSPKCS11TokenLabel string `toml:"tcp_tls_pkcs11_token_label"`
+	// TCPTLSPKCS11KeyLabel identifies the key/certificate pair on the
+	// token.
+	TCPTLSPKCS11KeyLabel string `toml:"tcp_tls_pkcs11_key_label"`
+	// TCPTLSPKCS11PinSource is a file containing the PIN used to log in to
+	// the token, preferred over TCPTLSPKCS11Pin so the PIN need not be
+	// stored in plaintext in this file.
+	TCPTLSPKCS11PinSource string `toml:"tcp_tls_pkcs11_pin_source"`
+	// TCPTLSPKCS11Pin is the PIN used to log in to the token, used when
+	// TCPTLSPKCS11PinSource is unset.
+	TCPTLSPKCS11Pin string `toml:"tcp_tls_pkcs11_pin"`
+
+	// TCPTLSKeychainName is the macOS keychain to look up
+	// TCPTLSKeychainLabel in. Defaults to the default keychain search list.
+	TCPTLSKeychainName string `toml:"tcp_tls_keychain_name"`
+	// TCPTLSKeychainLabel identifies the identity in the keychain used by
+	// the "macos-keychain" provider.
+	TCPTLSKeychainLabel string `toml:"tcp_tls_keychain_label"`
+
+	// TCPTLSTPM2Device is the TPM2 device node used by the "tpm2" provider.
+	// Defaults to /dev/tpmrm0.
+	TCPTLSTPM2Device string `toml:"tcp_tls_tpm2_device"`
+	// TCPTLSTPM2Handle is the persistent handle (e.g. "0x81000001") of the
+	// key loaded from the TPM.
+	TCPTLSTPM2Handle string `toml:"tcp_tls_tpm2_handle"`
+	// TCPTLSTPM2CertFile is the path to the certificate for the TPM2-backed
+	// key, since the TPM itself does not store one.
+	TCPTLSTPM2CertFile string `toml:"tcp_tls_tpm2_cert_file"`
+	// TCPTLSTPM2PKCS11, when set, accesses the TPM through the tpm2-pkcs11
+	// middleware (delegating to the "pkcs11" provider) instead of directly
+	// via go-tpm.
+	TCPTLSTPM2PKCS11 bool `toml:"tcp_tls_tpm2_pkcs11"`
+}
+
+// Validate checks cross-field constraints on c that can't be expressed
+// through the struct tags alone.
+func (c *GRPCConfig) Validate() error {
+	if c.TCPTLSACMEEnroll && c.TCPTLSACMEDirectoryURL == "" {
+		return fmt.Errorf("grpc.tcp_tls_acme_directory_url is required when grpc.tcp_tls_acme_enroll is set")
+	}
+	if c.TCPTLSACMEEnroll && c.TCPTLSProvider != "" {
+		return fmt.Errorf("grpc.tcp_tls_acme_enroll is only supported for the legacy Windows Certificate Store lookup, not alongside grpc.tcp_tls_provider %q", c.TCPTLSProvider)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ package server
 
 import (
 	"context"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -39,21 +40,79 @@ func apply(_ context.Context, _ *srvconfig.Config) error {
 }
 
 func newTTRPCServer() (*ttrpc.Server, error) {
-	return ttrpc.NewServer(
+	srv, err := ttrpc.NewServer(
 		ttrpc.WithUnaryServerInterceptor(otelttrpc.UnaryServerInterceptor()),
 	)
+	if err != nil {
+		return nil, err
+	}
+	registerTLSReloadService(srv)
+	return srv, nil
 }
 
 // setupTLSFromWindowsCertStore sets up TLS configuration using certificates from Windows Certificate Store.
+//
+// The certificate is not read once and baked into the gRPC credentials:
+// Windows cert stores are rotated out from under containerd by GPO/autoenroll,
+// so the lookup is wrapped in a tlsCertCache that periodically re-queries the
+// store and can also be force-refreshed through the TLSReload ttrpc call.
 func setupTLSFromWindowsCertStore(ctx context.Context, config *srvconfig.Config) ([]grpc.ServerOption, error) {
-	var tcpServerOpts []grpc.ServerOption
+	if err := config.GRPC.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid grpc configuration: %w", err)
+	}
 
 	log.G(ctx).Infof("Setting up TLS on TCP gRPC services with common name %v", config.GRPC.TCPTLSCName)
 
+	if err := enrollWindowsCertificateIfMissing(ctx, config); err != nil {
+		return nil, fmt.Errorf("failed to enroll TLS certificate: %w", err)
+	}
+
+	cache, err := newTLSCertCache(ctx, config.GRPC.TCPTLSRefreshInterval, func(ctx context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		certContext, leafCert, err := findWindowsCertificate(ctx, &config.GRPC)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer windows.CertFreeCertificateContext(certContext)
+
+		certPool, certChainBytes, err := windowsCertificateChain(ctx, certContext)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key, err := windowsCertificateKey(ctx, leafCert, certContext)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tlsCert := &tls.Certificate{
+			PrivateKey:  key,
+			Leaf:        leafCert,
+			Certificate: append([][]byte{leafCert.Raw}, certChainBytes...),
+		}
+
+		if len(certChainBytes) == 0 {
+			certPool = nil
+		}
+
+		return tlsCert, certPool, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS configuration from Windows Certificate Store: %w", err)
+	}
+	setActiveTLSCertCache(cache)
+
+	log.G(ctx).Infof("Loaded TLS configuration successfully")
+
+	tcpServerOpts := append([]grpc.ServerOption{grpc.Creds(credentials.NewTLS(cache.TLSConfig()))}, grpcServerOptionsForAuthz(&config.GRPC)...)
+	return tcpServerOpts, nil
+}
+
+// openWindowsCertStore opens the LocalMachine "My" certificate store.
+func openWindowsCertStore(ctx context.Context) (windows.Handle, error) {
 	storeName, err := syscall.UTF16PtrFromString("My")
 	if err != nil {
 		log.G(ctx).WithError(err).Errorf("failed to convert store name to UTF16")
-		return nil, fmt.Errorf("failed to convert store name to UTF16: %w", err)
+		return 0, fmt.Errorf("failed to convert store name to UTF16: %w", err)
 	}
 
 	winStore, err := windows.CertOpenStore(windows.CERT_STORE_PROV_SYSTEM,
@@ -61,46 +120,26 @@ func setupTLSFromWindowsCertStore(ctx context.Context, config *srvconfig.Config)
 		windows.CERT_SYSTEM_STORE_LOCAL_MACHINE, uintptr(unsafe.Pointer(storeName)))
 	if err != nil {
 		log.G(ctx).WithError(err).Errorf("failed to open certificate store")
-		return nil, fmt.Errorf("failed to open windows certificate store: %w", err)
-	}
-	defer windows.CertCloseStore(winStore, 0)
-
-	commonName, err := syscall.UTF16PtrFromString(config.GRPC.TCPTLSCName)
-	if err != nil {
-		log.G(ctx).WithError(err).Errorf("failed to convert common name to UTF16")
-		return nil, fmt.Errorf("failed to convert common name to UTF16: %w", err)
-	}
-
-	// Find the certificate by common name in the Windows Certificate Store.
-	certContext, err := windows.CertFindCertificateInStore(winStore,
-		windows.X509_ASN_ENCODING|windows.PKCS_7_ASN_ENCODING,
-		0, windows.CERT_FIND_SUBJECT_STR, unsafe.Pointer(commonName), nil)
-	if err != nil || certContext == nil {
-		log.G(ctx).WithError(err).Errorf("failed to find certificate in store")
-		return nil, fmt.Errorf("failed to find certificate in store: %w", err)
-	}
-	defer windows.CertFreeCertificateContext(certContext)
-
-	// Parse the leaf certificate from certContext
-	certDER := unsafe.Slice(certContext.EncodedCert, certContext.Length)
-	leafCert, err := x509.ParseCertificate(certDER)
-	if err != nil {
-		log.G(ctx).WithError(err).Errorf("failed to parse leaf certificate")
-		return nil, fmt.Errorf("failed to parse leaf certificate: %w", err)
+		return 0, fmt.Errorf("failed to open windows certificate store: %w", err)
 	}
+	return winStore, nil
+}
 
-	// Retrieve the certificate chain
+// windowsCertificateChain retrieves the certificate chain for certContext,
+// returning both an x509.CertPool of the intermediates (suitable for use as
+// ClientCAs) and their raw DER bytes (suitable for use in a
+// tls.Certificate's Certificate field).
+func windowsCertificateChain(ctx context.Context, certContext *windows.CertContext) (*x509.CertPool, [][]byte, error) {
 	var certChain *windows.CertChainContext
 	var chainPara windows.CertChainPara
 	chainPara.Size = uint32(unsafe.Sizeof(chainPara))
-	err = windows.CertGetCertificateChain(0, certContext, nil, 0, &chainPara, 0, 0, &certChain)
+	err := windows.CertGetCertificateChain(0, certContext, nil, 0, &chainPara, 0, 0, &certChain)
 	if err != nil {
 		log.G(ctx).WithError(err).Errorf("failed to retrieve certificate chain")
-		return nil, fmt.Errorf("failed to retrieve certificate chain: %w", err)
+		return nil, nil, fmt.Errorf("failed to retrieve certificate chain: %w", err)
 	}
 	defer windows.CertFreeCertificateChain(certChain)
 
-	// Convert the certificate chain to a Go x509.CertPool and create certificate pool
 	certPool := x509.NewCertPool()
 	chains := unsafe.Slice(certChain.Chains, certChain.ChainCount)
 	var certChainBytes [][]byte
@@ -117,14 +156,20 @@ func setupTLSFromWindowsCertStore(ctx context.Context, config *srvconfig.Config)
 			cert, err := x509.ParseCertificate(certBytes)
 			if err != nil {
 				log.G(ctx).WithError(err).Errorf("failed to parse certificate from chain")
-				return nil, fmt.Errorf("failed to parse certificate from chain: %v", err)
+				return nil, nil, fmt.Errorf("failed to parse certificate from chain: %v", err)
 			}
 			certPool.AddCert(cert)
 		}
 	}
 
-	// Open the Windows Certificate Store to retrieve the private key. certtostore implements crypto.Signer
-	// and crypto.Decrypter interfaces for private key operations.
+	return certPool, certChainBytes, nil
+}
+
+// windowsCertificateKey opens the Windows Certificate Store to retrieve the
+// private key for leafCert. certtostore implements the crypto.Signer and
+// crypto.Decrypter interfaces for private key operations, so the key never
+// leaves the store.
+func windowsCertificateKey(ctx context.Context, leafCert *x509.Certificate, certContext *windows.CertContext) (crypto.Signer, error) {
 	store, err := certtostore.OpenWinCertStore(certtostore.ProviderMSSoftware, "",
 		[]string{leafCert.Issuer.CommonName}, nil, false)
 	if err != nil {
@@ -139,24 +184,5 @@ func setupTLSFromWindowsCertStore(ctx context.Context, config *srvconfig.Config)
 		return nil, fmt.Errorf("failed to retrieve private key: %w", err)
 	}
 
-	tlsCert := tls.Certificate{
-		PrivateKey:  key,
-		Leaf:        leafCert,
-		Certificate: append([][]byte{leafCert.Raw}, certChainBytes...),
-	}
-
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{tlsCert},
-		ClientCAs:    certPool,
-	}
-
-	if len(certChainBytes) > 0 {
-		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
-	}
-
-	tcpServerOpts = append(tcpServerOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
-
-	log.G(ctx).Infof("Loaded TLS configuration successfully")
-
-	return tcpServerOpts, nil
+	return key, nil
 }
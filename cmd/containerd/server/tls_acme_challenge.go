@@ -0,0 +1,205 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	srvconfig "github.com/containerd/containerd/v2/cmd/containerd/server/config"
+	"golang.org/x/crypto/acme"
+)
+
+// completeACMEChallenges walks order's authorizations and completes either
+// a tls-alpn-01 or an http-01 challenge, depending on which the CA offers,
+// via the responder named by config.TCPTLSACMEChallengeResponder, or the
+// built-in responder for the selected challenge's type when that is unset.
+// It has no platform-specific dependency, unlike the enrollment flow that
+// calls it.
+func completeACMEChallenges(ctx context.Context, client *acme.Client, config *srvconfig.GRPCConfig, order *acme.Order) error {
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return fmt.Errorf("failed to get ACME authorization: %w", err)
+		}
+
+		chal, err := selectACMEChallenge(authz, config.TCPTLSACMEChallengeType)
+		if err != nil {
+			return err
+		}
+
+		responderName := config.TCPTLSACMEChallengeResponder
+		if responderName == "" {
+			responderName = chal.Type
+		}
+		responder, err := resolveACMEChallengeResponder(responderName)
+		if err != nil {
+			return err
+		}
+
+		stop, err := responder.Respond(ctx, client, config, chal)
+		if err != nil {
+			return fmt.Errorf("failed to respond to %s challenge: %w", chal.Type, err)
+		}
+
+		if _, err := client.Accept(ctx, chal); err != nil {
+			stop()
+			return fmt.Errorf("failed to accept %s challenge: %w", chal.Type, err)
+		}
+
+		_, err = client.WaitAuthorization(ctx, authzURL)
+		stop()
+		if err != nil {
+			return fmt.Errorf("authorization %s did not complete: %w", authzURL, err)
+		}
+	}
+
+	return nil
+}
+
+func selectACMEChallenge(authz *acme.Authorization, preferredType string) (*acme.Challenge, error) {
+	if preferredType == "" {
+		preferredType = "tls-alpn-01"
+	}
+
+	for _, c := range authz.Challenges {
+		if c.Type == preferredType {
+			return c, nil
+		}
+	}
+	for _, c := range authz.Challenges {
+		if c.Type == "http-01" {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no usable challenge type offered for authorization %s", authz.URI)
+}
+
+// acmeChallengeResponder is implemented by the tls-alpn-01 and http-01
+// responders so that the challenge-completion path above does not need to
+// know how the challenge response is actually served. Respond starts
+// serving the response in the background and returns a stop function the
+// caller must invoke once the CA has validated (or abandoned) the
+// challenge.
+type acmeChallengeResponder interface {
+	Respond(ctx context.Context, client *acme.Client, config *srvconfig.GRPCConfig, chal *acme.Challenge) (stop func(), err error)
+}
+
+// resolveACMEChallengeResponder resolves the named responder. "tls-alpn-01"
+// and "http-01" are built in; any other name is rejected with an error.
+func resolveACMEChallengeResponder(name string) (acmeChallengeResponder, error) {
+	switch name {
+	case "", "tls-alpn-01":
+		return tlsALPN01Responder{}, nil
+	case "http-01":
+		return http01Responder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ACME challenge responder %q", name)
+	}
+}
+
+// tlsALPN01Responder completes the tls-alpn-01 challenge by serving the
+// challenge certificate on its own listener at
+// config.TCPTLSACMETLSALPNAddress until the returned stop function is called.
+type tlsALPN01Responder struct{}
+
+func (tlsALPN01Responder) Respond(ctx context.Context, client *acme.Client, config *srvconfig.GRPCConfig, chal *acme.Challenge) (func(), error) {
+	cert, err := client.TLSALPN01ChallengeCert(chal.Token, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tls-alpn-01 challenge certificate: %w", err)
+	}
+	return serveACMEChallengeCertificate(config.TCPTLSACMETLSALPNAddress, &cert)
+}
+
+// http01Responder completes the http-01 challenge by serving the challenge
+// response on the well-known ACME path.
+type http01Responder struct{}
+
+func (http01Responder) Respond(ctx context.Context, client *acme.Client, config *srvconfig.GRPCConfig, chal *acme.Challenge) (func(), error) {
+	response, err := client.HTTP01ChallengeResponse(chal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http-01 challenge response: %w", err)
+	}
+	return serveACMEChallengeResponse(config.TCPTLSACMEHTTPAddress, client.HTTP01ChallengePath(chal.Token), response)
+}
+
+// serveACMEChallengeCertificate listens on addr (defaulting to :443) for
+// the tls-alpn-01 handshake, presenting cert for any ClientHello that
+// negotiates the "acme-tls/1" protocol, until the returned stop function
+// is called. addr must be configured away from :443 when the TCP gRPC
+// endpoint itself listens there, or renewal will fail with "address
+// already in use" while the server is running.
+func serveACMEChallengeCertificate(addr string, cert *tls.Certificate) (func(), error) {
+	if addr == "" {
+		addr = ":443"
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for tls-alpn-01 challenge: %w", err)
+	}
+
+	tlsLn := tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{*cert},
+		NextProtos:   []string{acme.ALPNProto},
+	})
+
+	go func() {
+		for {
+			conn, err := tlsLn.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}(conn)
+		}
+	}()
+
+	return func() { tlsLn.Close() }, nil
+}
+
+// serveACMEChallengeResponse listens on addr (defaulting to :80) and serves
+// response at path for the http-01 challenge, until the returned stop
+// function is called. addr must be configured away from :80 when the TCP
+// gRPC endpoint itself listens there, or renewal will fail with "address
+// already in use" while the server is running.
+func serveACMEChallengeResponse(addr, path, response string) (func(), error) {
+	if addr == "" {
+		addr = ":80"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(response))
+	})
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for http-01 challenge: %w", err)
+	}
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+
+	return func() { srv.Close() }, nil
+}
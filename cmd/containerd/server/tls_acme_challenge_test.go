@@ -0,0 +1,108 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/acme"
+)
+
+func TestSelectACMEChallenge(t *testing.T) {
+	tlsALPN01 := &acme.Challenge{Type: "tls-alpn-01"}
+	http01 := &acme.Challenge{Type: "http-01"}
+	dns01 := &acme.Challenge{Type: "dns-01"}
+
+	tests := []struct {
+		name          string
+		authz         *acme.Authorization
+		preferredType string
+		want          *acme.Challenge
+		wantErr       bool
+	}{
+		{
+			name:  "defaults to tls-alpn-01 when offered",
+			authz: &acme.Authorization{Challenges: []*acme.Challenge{http01, tlsALPN01}},
+			want:  tlsALPN01,
+		},
+		{
+			name:  "falls back to http-01 when tls-alpn-01 is not offered",
+			authz: &acme.Authorization{Challenges: []*acme.Challenge{dns01, http01}},
+			want:  http01,
+		},
+		{
+			name:          "honors an explicit preferred type",
+			authz:         &acme.Authorization{Challenges: []*acme.Challenge{tlsALPN01, http01}},
+			preferredType: "http-01",
+			want:          http01,
+		},
+		{
+			name:    "no usable challenge offered",
+			authz:   &acme.Authorization{URI: "https://example.org/authz/1", Challenges: []*acme.Challenge{dns01}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectACMEChallenge(tt.authz, tt.preferredType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("selectACMEChallenge() = nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectACMEChallenge() = %v, want nil", err)
+			}
+			if got != tt.want {
+				t.Fatalf("selectACMEChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveACMEChallengeResponder(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    acmeChallengeResponder
+		wantErr bool
+	}{
+		{name: "", want: tlsALPN01Responder{}},
+		{name: "tls-alpn-01", want: tlsALPN01Responder{}},
+		{name: "http-01", want: http01Responder{}},
+		{name: "unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveACMEChallengeResponder(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("resolveACMEChallengeResponder() = nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveACMEChallengeResponder() = %v, want nil", err)
+			}
+			if got != tt.want {
+				t.Fatalf("resolveACMEChallengeResponder() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+
+	srvconfig "github.com/containerd/containerd/v2/cmd/containerd/server/config"
+	"github.com/containerd/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// tlsPeerAuthorizer checks an mTLS peer's verified certificate against the
+// configured allowlist of SPIFFE IDs, DNS SANs and organizational units
+// before letting a gRPC call through. Presenting a certificate that chains
+// to the trusted CA pool is necessary but, with an allowlist configured,
+// no longer sufficient on its own.
+type tlsPeerAuthorizer struct {
+	allowedSPIFFEIDs map[string]struct{}
+	allowedDNSNames  map[string]struct{}
+	allowedOUs       map[string]struct{}
+}
+
+// newTLSPeerAuthorizer builds a tlsPeerAuthorizer from the
+// TCPTLSAllowedSPIFFEIDs/TCPTLSAllowedDNSNames/TCPTLSAllowedOUs lists in
+// config.GRPC. It returns nil, meaning "allow any peer that chains to the
+// CA pool", when none of the three lists are configured.
+func newTLSPeerAuthorizer(config *srvconfig.GRPCConfig) *tlsPeerAuthorizer {
+	if len(config.TCPTLSAllowedSPIFFEIDs) == 0 && len(config.TCPTLSAllowedDNSNames) == 0 && len(config.TCPTLSAllowedOUs) == 0 {
+		return nil
+	}
+
+	a := &tlsPeerAuthorizer{
+		allowedSPIFFEIDs: toSet(config.TCPTLSAllowedSPIFFEIDs),
+		allowedDNSNames:  toSet(config.TCPTLSAllowedDNSNames),
+		allowedOUs:       toSet(config.TCPTLSAllowedOUs),
+	}
+	return a
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// authorize returns nil if cert satisfies the allowlist, or a
+// codes.PermissionDenied error describing what was checked.
+func (a *tlsPeerAuthorizer) authorize(cert *x509.Certificate) error {
+	if len(a.allowedSPIFFEIDs) > 0 {
+		for _, uri := range cert.URIs {
+			if uri.Scheme != "spiffe" {
+				continue
+			}
+			if _, ok := a.allowedSPIFFEIDs[uri.String()]; ok {
+				return nil
+			}
+		}
+	}
+
+	if len(a.allowedDNSNames) > 0 {
+		for _, name := range cert.DNSNames {
+			if _, ok := a.allowedDNSNames[name]; ok {
+				return nil
+			}
+		}
+	}
+
+	if len(a.allowedOUs) > 0 {
+		for _, ou := range cert.Subject.OrganizationalUnit {
+			if _, ok := a.allowedOUs[ou]; ok {
+				return nil
+			}
+		}
+	}
+
+	return status.Errorf(codes.PermissionDenied, "peer certificate (serial %x) does not match any allowed SPIFFE ID, DNS name or OU", cert.SerialNumber)
+}
+
+// peerCertificate extracts the verified leaf certificate from ctx's gRPC
+// peer info, as populated by credentials.NewTLS with ClientAuth set to
+// RequireAndVerifyClientCert.
+func peerCertificate(ctx context.Context) (*x509.Certificate, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "no peer information in context")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, status.Error(codes.PermissionDenied, "no verified client certificate presented")
+	}
+
+	return tlsInfo.State.VerifiedChains[0][0], nil
+}
+
+func auditLogCertificate(ctx context.Context, cert *x509.Certificate, method string, err error) {
+	thumbprint := sha256.Sum256(cert.Raw)
+	fields := log.G(ctx).WithFields(map[string]interface{}{
+		"method":     method,
+		"serial":     cert.SerialNumber.String(),
+		"thumbprint": hex.EncodeToString(thumbprint[:]),
+		"subject":    cert.Subject.String(),
+	})
+	if err != nil {
+		fields.WithError(err).Warn("denied mTLS client")
+		return
+	}
+	fields.Debug("authorized mTLS client")
+}
+
+// UnaryServerInterceptor enforces the TLS peer authorization policy for
+// unary gRPC calls.
+func (a *tlsPeerAuthorizer) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	cert, err := peerCertificate(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).WithField("method", info.FullMethod).Warn("denied mTLS client")
+		return nil, err
+	}
+
+	if err := a.authorize(cert); err != nil {
+		auditLogCertificate(ctx, cert, info.FullMethod, err)
+		return nil, err
+	}
+
+	auditLogCertificate(ctx, cert, info.FullMethod, nil)
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor enforces the TLS peer authorization policy for
+// streaming gRPC calls.
+func (a *tlsPeerAuthorizer) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := ss.Context()
+	cert, err := peerCertificate(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).WithField("method", info.FullMethod).Warn("denied mTLS client")
+		return err
+	}
+
+	if err := a.authorize(cert); err != nil {
+		auditLogCertificate(ctx, cert, info.FullMethod, err)
+		return err
+	}
+
+	auditLogCertificate(ctx, cert, info.FullMethod, nil)
+	return handler(srv, ss)
+}
+
+// grpcServerOptionsForAuthz returns the grpc.ServerOptions that enforce
+// config's TLS peer authorization policy, or nil if no allowlist is
+// configured.
+func grpcServerOptionsForAuthz(config *srvconfig.GRPCConfig) []grpc.ServerOption {
+	authz := newTLSPeerAuthorizer(config)
+	if authz == nil {
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(authz.UnaryServerInterceptor),
+		grpc.ChainStreamInterceptor(authz.StreamServerInterceptor),
+	}
+}
@@ -0,0 +1,99 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTLSPeerAuthorizerAuthorize(t *testing.T) {
+	spiffeID, err := url.Parse("spiffe://example.org/ns/default/sa/client")
+	if err != nil {
+		t.Fatalf("failed to parse test SPIFFE ID: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		a       *tlsPeerAuthorizer
+		cert    *x509.Certificate
+		wantErr bool
+	}{
+		{
+			name: "matches allowed SPIFFE ID",
+			a:    &tlsPeerAuthorizer{allowedSPIFFEIDs: toSet([]string{spiffeID.String()})},
+			cert: &x509.Certificate{URIs: []*url.URL{spiffeID}},
+		},
+		{
+			name:    "does not match any allowed SPIFFE ID",
+			a:       &tlsPeerAuthorizer{allowedSPIFFEIDs: toSet([]string{"spiffe://example.org/ns/default/sa/other"})},
+			cert:    &x509.Certificate{URIs: []*url.URL{spiffeID}},
+			wantErr: true,
+		},
+		{
+			name: "matches allowed DNS name",
+			a:    &tlsPeerAuthorizer{allowedDNSNames: toSet([]string{"host.example.org"})},
+			cert: &x509.Certificate{DNSNames: []string{"host.example.org"}},
+		},
+		{
+			name:    "does not match any allowed DNS name",
+			a:       &tlsPeerAuthorizer{allowedDNSNames: toSet([]string{"host.example.org"})},
+			cert:    &x509.Certificate{DNSNames: []string{"other.example.org"}},
+			wantErr: true,
+		},
+		{
+			name: "matches allowed OU",
+			a:    &tlsPeerAuthorizer{allowedOUs: toSet([]string{"engineering"})},
+			cert: &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: []string{"engineering"}}},
+		},
+		{
+			name:    "does not match any allowed OU",
+			a:       &tlsPeerAuthorizer{allowedOUs: toSet([]string{"engineering"})},
+			cert:    &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: []string{"sales"}}},
+			wantErr: true,
+		},
+		{
+			name:    "no lists configured denies by default",
+			a:       &tlsPeerAuthorizer{},
+			cert:    &x509.Certificate{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.a.authorize(tt.cert)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("authorize() = nil, want PermissionDenied error")
+				}
+				if status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("authorize() code = %v, want %v", status.Code(err), codes.PermissionDenied)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("authorize() = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/x509"
+	"time"
+)
+
+// windowsCertificateInvalidReason reports why a candidate certificate
+// should be skipped under TCPTLSCertSkipInvalid: expiry, not-yet-valid, or
+// missing the server authentication EKU. It has no dependency on the
+// Windows Certificate Store itself so it lives outside
+// tls_certmatch_windows.go, alongside its tests.
+func windowsCertificateInvalidReason(cert *x509.Certificate) (string, bool) {
+	now := time.Now()
+	if now.Before(cert.NotBefore) {
+		return "not yet valid", true
+	}
+	if now.After(cert.NotAfter) {
+		return "expired", true
+	}
+	if len(cert.ExtKeyUsage) > 0 && !hasServerAuthEKU(cert) {
+		return "missing serverAuth extended key usage", true
+	}
+	return "", false
+}
+
+func hasServerAuthEKU(cert *x509.Certificate) bool {
+	for _, eku := range cert.ExtKeyUsage {
+		if eku == x509.ExtKeyUsageServerAuth || eku == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestWindowsCertificateInvalidReason(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		cert   *x509.Certificate
+		reason string
+		want   bool
+	}{
+		{
+			name: "valid certificate with serverAuth EKU",
+			cert: &x509.Certificate{
+				NotBefore:   now.Add(-time.Hour),
+				NotAfter:    now.Add(time.Hour),
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			},
+		},
+		{
+			name: "valid certificate with no EKU restriction",
+			cert: &x509.Certificate{
+				NotBefore: now.Add(-time.Hour),
+				NotAfter:  now.Add(time.Hour),
+			},
+		},
+		{
+			name: "not yet valid",
+			cert: &x509.Certificate{
+				NotBefore: now.Add(time.Hour),
+				NotAfter:  now.Add(2 * time.Hour),
+			},
+			reason: "not yet valid",
+			want:   true,
+		},
+		{
+			name: "expired",
+			cert: &x509.Certificate{
+				NotBefore: now.Add(-2 * time.Hour),
+				NotAfter:  now.Add(-time.Hour),
+			},
+			reason: "expired",
+			want:   true,
+		},
+		{
+			name: "missing serverAuth EKU",
+			cert: &x509.Certificate{
+				NotBefore:   now.Add(-time.Hour),
+				NotAfter:    now.Add(time.Hour),
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			},
+			reason: "missing serverAuth extended key usage",
+			want:   true,
+		},
+		{
+			name: "any EKU satisfies serverAuth",
+			cert: &x509.Certificate{
+				NotBefore:   now.Add(-time.Hour),
+				NotAfter:    now.Add(time.Hour),
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, ok := windowsCertificateInvalidReason(tt.cert)
+			if ok != tt.want {
+				t.Fatalf("windowsCertificateInvalidReason() ok = %v, want %v", ok, tt.want)
+			}
+			if reason != tt.reason {
+				t.Fatalf("windowsCertificateInvalidReason() reason = %q, want %q", reason, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	srvconfig "github.com/containerd/containerd/v2/cmd/containerd/server/config"
+	"github.com/containerd/log"
+	"golang.org/x/sys/windows"
+)
+
+// Recognized values for config.GRPC.TCPTLSCertMatchBy.
+const (
+	certMatchBySubject    = "subject"
+	certMatchByIssuer     = "issuer"
+	certMatchByThumbprint = "thumbprint"
+)
+
+// findWindowsCertificate locates the configured certificate in the Windows
+// Certificate Store according to config.TCPTLSCertMatchBy /
+// config.TCPTLSCertMatch, falling back to the legacy subject-CN lookup
+// (config.TCPTLSCName) when no match mode is configured. When
+// config.TCPTLSCertSkipInvalid is set and several certificates satisfy the
+// match, expired, not-yet-valid or wrong-EKU certificates are skipped in
+// favor of the next candidate.
+func findWindowsCertificate(ctx context.Context, config *srvconfig.GRPCConfig) (*windows.CertContext, *x509.Certificate, error) {
+	matchBy := config.TCPTLSCertMatchBy
+	match := config.TCPTLSCertMatch
+	if matchBy == "" {
+		matchBy = certMatchBySubject
+	}
+	if match == "" {
+		// Preserve behavior for configurations written before
+		// TCPTLSCertMatchBy/TCPTLSCertMatch existed.
+		match = config.TCPTLSCName
+	}
+
+	var findType uint32
+	var findPara unsafe.Pointer
+
+	switch matchBy {
+	case certMatchBySubject:
+		cn, err := syscall.UTF16PtrFromString(match)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert common name to UTF16: %w", err)
+		}
+		findType = windows.CERT_FIND_SUBJECT_STR
+		findPara = unsafe.Pointer(cn)
+	case certMatchByIssuer:
+		issuer, err := syscall.UTF16PtrFromString(match)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert issuer to UTF16: %w", err)
+		}
+		findType = windows.CERT_FIND_ISSUER_STR
+		findPara = unsafe.Pointer(issuer)
+	case certMatchByThumbprint:
+		thumbprint, err := hex.DecodeString(match)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode thumbprint %q as hex: %w", match, err)
+		}
+		if len(thumbprint) == 0 {
+			return nil, nil, fmt.Errorf("TCPTLSCertMatch is empty, a thumbprint is required when TCPTLSCertMatchBy is %q", certMatchByThumbprint)
+		}
+		hashBlob := windows.CryptHashBlob{
+			Data: (*byte)(unsafe.Pointer(&thumbprint[0])),
+			Size: uint32(len(thumbprint)),
+		}
+		findType = windows.CERT_FIND_HASH
+		findPara = unsafe.Pointer(&hashBlob)
+	default:
+		return nil, nil, fmt.Errorf("unknown TCPTLSCertMatchBy %q, must be one of %q, %q, %q",
+			matchBy, certMatchBySubject, certMatchByIssuer, certMatchByThumbprint)
+	}
+
+	winStore, err := openWindowsCertStore(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer windows.CertCloseStore(winStore, 0)
+
+	var prev *windows.CertContext
+	for {
+		certContext, err := windows.CertFindCertificateInStore(winStore,
+			windows.X509_ASN_ENCODING|windows.PKCS_7_ASN_ENCODING, 0, findType, findPara, prev)
+		if err != nil || certContext == nil {
+			if prev != nil {
+				log.G(ctx).Warnf("no valid certificate matched %s=%q after skipping invalid candidates", matchBy, match)
+			}
+			log.G(ctx).WithError(err).Errorf("failed to find certificate in store")
+			return nil, nil, fmt.Errorf("failed to find certificate in store: %w", err)
+		}
+		prev = certContext
+
+		certDER := unsafe.Slice(certContext.EncodedCert, certContext.Length)
+		leafCert, err := x509.ParseCertificate(certDER)
+		if err != nil {
+			windows.CertFreeCertificateContext(certContext)
+			return nil, nil, fmt.Errorf("failed to parse leaf certificate: %w", err)
+		}
+
+		if !config.TCPTLSCertSkipInvalid {
+			return certContext, leafCert, nil
+		}
+
+		if reason, ok := windowsCertificateInvalidReason(leafCert); ok {
+			log.G(ctx).Warnf("skipping certificate %x (%s): %s", leafCert.SerialNumber, leafCert.Subject, reason)
+			continue
+		}
+
+		log.G(ctx).Infof("selected certificate %x (%s) matching %s=%q", leafCert.SerialNumber, leafCert.Subject, matchBy, match)
+		return certContext, leafCert, nil
+	}
+}
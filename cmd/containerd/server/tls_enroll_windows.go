@@ -0,0 +1,169 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"time"
+
+	srvconfig "github.com/containerd/containerd/v2/cmd/containerd/server/config"
+	"github.com/containerd/log"
+	"github.com/google/certtostore"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/sys/windows"
+)
+
+// enrollWindowsCertificateIfMissing is called by setupTLSFromWindowsCertStore
+// before its first certificate lookup. When config.GRPC.TCPTLSACMEEnroll is set and
+// no certificate for config.GRPC.TCPTLSCName exists yet in the LocalMachine
+// "My" store, it obtains one from the ACME CA at
+// config.GRPC.TCPTLSACMEDirectoryURL and imports it so that operators do not
+// have to pre-stage a certificate by hand. The private key is generated
+// on, and never leaves, the CNG key store. Either way, as long as
+// TCPTLSACMEEnroll is set, it starts renewWindowsCertificateLoop so the
+// certificate keeps renewing across restarts rather than only for the
+// lifetime of the process that performed the initial enrollment.
+func enrollWindowsCertificateIfMissing(ctx context.Context, config *srvconfig.Config) error {
+	if !config.GRPC.TCPTLSACMEEnroll {
+		return nil
+	}
+
+	if certContext, _, err := findWindowsCertificate(ctx, &config.GRPC); err == nil {
+		windows.CertFreeCertificateContext(certContext)
+		go renewWindowsCertificateLoop(ctx, config)
+		return nil
+	}
+
+	log.G(ctx).Infof("no certificate for %q in Windows Certificate Store, enrolling via ACME", config.GRPC.TCPTLSCName)
+
+	if err := acmeEnrollAndStore(ctx, &config.GRPC); err != nil {
+		return fmt.Errorf("failed to enroll certificate via ACME: %w", err)
+	}
+
+	go renewWindowsCertificateLoop(ctx, config)
+
+	return nil
+}
+
+// acmeEnrollAndStore generates a CNG-protected key pair in the Windows
+// Certificate Store, obtains a certificate for it from the ACME CA, and
+// imports the certificate with StoreWithDisposition so the private key
+// remains bound to the store.
+func acmeEnrollAndStore(ctx context.Context, config *srvconfig.GRPCConfig) error {
+	store, err := certtostore.OpenWinCertStore(certtostore.ProviderMSSoftware, "", nil, nil, false)
+	if err != nil {
+		return fmt.Errorf("failed to open Windows Certificate Store: %w", err)
+	}
+	defer store.Close()
+
+	// The ACME account and challenge-response keys are ephemeral and never
+	// stored; only the certificate's own key, generated below, ends up in
+	// the CNG store.
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate ACME account key: %w", err)
+	}
+
+	client := &acme.Client{Key: accountKey, DirectoryURL: config.TCPTLSACMEDirectoryURL}
+	if _, err := client.Register(ctx, &acme.Account{}, acme.AcceptTOS); err != nil {
+		return fmt.Errorf("failed to register ACME account: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, []acme.AuthzID{{Type: "dns", Value: config.TCPTLSCName}})
+	if err != nil {
+		return fmt.Errorf("failed to authorize ACME order for %q: %w", config.TCPTLSCName, err)
+	}
+
+	if err := completeACMEChallenges(ctx, client, config, order); err != nil {
+		return fmt.Errorf("failed to complete ACME challenges: %w", err)
+	}
+
+	certKey, err := store.GenerateECDSAKey(config.TCPTLSCName, elliptic.P256())
+	if err != nil {
+		return fmt.Errorf("failed to generate CNG-protected key for %q: %w", config.TCPTLSCName, err)
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: config.TCPTLSCName},
+	}, certKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("failed to finalize ACME order: %w", err)
+	}
+	if len(der) == 0 {
+		return fmt.Errorf("ACME CA returned no certificate for %q", config.TCPTLSCName)
+	}
+
+	if err := store.StoreWithDisposition(der[0], config.TCPTLSCName, certtostore.StoreAddReplaceExisting); err != nil {
+		return fmt.Errorf("failed to import enrolled certificate: %w", err)
+	}
+
+	log.G(ctx).Infof("enrolled and stored certificate for %q via ACME", config.TCPTLSCName)
+	return nil
+}
+
+// renewWindowsCertificateLoop re-enrolls config.GRPC.TCPTLSCName's
+// certificate at roughly 2/3 of its lifetime and swaps the active
+// certificate via the hot-reload cache, so renewal never requires
+// restarting containerd.
+func renewWindowsCertificateLoop(ctx context.Context, config *srvconfig.Config) {
+	for {
+		certContext, leaf, err := findWindowsCertificate(ctx, &config.GRPC)
+		if err != nil {
+			log.G(ctx).WithError(err).Error("failed to read enrolled certificate for renewal scheduling")
+			return
+		}
+		lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+		renewAt := leaf.NotBefore.Add(lifetime * 2 / 3)
+		windows.CertFreeCertificateContext(certContext)
+
+		wait := time.Until(renewAt)
+		if wait < 0 {
+			wait = 0
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+
+		if err := acmeEnrollAndStore(ctx, &config.GRPC); err != nil {
+			log.G(ctx).WithError(err).Error("failed to renew ACME certificate")
+			continue
+		}
+
+		activeTLSCertCacheMu.Lock()
+		cache := activeTLSCertCache
+		activeTLSCertCacheMu.Unlock()
+		if cache != nil {
+			if err := cache.Reload(ctx); err != nil {
+				log.G(ctx).WithError(err).Error("failed to hot-swap renewed certificate")
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	srvconfig "github.com/containerd/containerd/v2/cmd/containerd/server/config"
+	"github.com/containerd/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSCredentialProvider supplies the private key, leaf certificate and
+// certificate chain used to configure the TCP gRPC server's TLS
+// credentials. Implementations keep the private key inside its backing
+// store (an HSM, a PKCS#11 token, the Windows Certificate Store, a
+// platform keychain, ...) and only ever hand back a crypto.Signer, so the
+// key material itself never leaves the store.
+type TLSCredentialProvider interface {
+	// Signer returns a crypto.Signer backed by the provider's private key.
+	Signer(ctx context.Context) (crypto.Signer, error)
+	// Leaf returns the leaf certificate associated with the signer.
+	Leaf(ctx context.Context) (*x509.Certificate, error)
+	// Chain returns the intermediate certificates, if any, that should be
+	// presented alongside the leaf certificate.
+	Chain(ctx context.Context) ([]*x509.Certificate, error)
+}
+
+// TLSCredentialProviderFactory constructs a TLSCredentialProvider from the
+// `grpc` section of the containerd configuration.
+type TLSCredentialProviderFactory func(ctx context.Context, config *srvconfig.GRPCConfig) (TLSCredentialProvider, error)
+
+var (
+	tlsProvidersMu sync.Mutex
+	tlsProviders   = map[string]TLSCredentialProviderFactory{}
+)
+
+// RegisterTLSCredentialProvider registers a named TLSCredentialProviderFactory
+// so that it can be selected with `grpc.tcp_tls_provider` in the containerd
+// configuration. Platform-specific providers call this from an init
+// function guarded by a build constraint.
+func RegisterTLSCredentialProvider(name string, factory TLSCredentialProviderFactory) {
+	tlsProvidersMu.Lock()
+	defer tlsProvidersMu.Unlock()
+	tlsProviders[name] = factory
+}
+
+// newTLSCredentialProvider looks up the provider named by
+// config.TCPTLSProvider and constructs it.
+func newTLSCredentialProvider(ctx context.Context, config *srvconfig.GRPCConfig) (TLSCredentialProvider, error) {
+	tlsProvidersMu.Lock()
+	factory, ok := tlsProviders[config.TCPTLSProvider]
+	tlsProvidersMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown TLS credential provider %q", config.TCPTLSProvider)
+	}
+	return factory(ctx, config)
+}
+
+// tlsCertificateFromProvider assembles a tls.Certificate from a
+// TLSCredentialProvider without ever touching raw key material: the
+// tls.Certificate's PrivateKey is the crypto.Signer itself.
+func tlsCertificateFromProvider(ctx context.Context, p TLSCredentialProvider) (tls.Certificate, error) {
+	signer, err := p.Signer(ctx)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to get signer from TLS credential provider: %w", err)
+	}
+
+	leaf, err := p.Leaf(ctx)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to get leaf certificate from TLS credential provider: %w", err)
+	}
+
+	chain, err := p.Chain(ctx)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to get certificate chain from TLS credential provider: %w", err)
+	}
+
+	raw := make([][]byte, 0, len(chain)+1)
+	raw = append(raw, leaf.Raw)
+	for _, c := range chain {
+		raw = append(raw, c.Raw)
+	}
+
+	return tls.Certificate{
+		PrivateKey:  signer,
+		Leaf:        leaf,
+		Certificate: raw,
+	}, nil
+}
+
+// setupTLSFromCredentialProvider builds the gRPC server TLS options from the
+// TLS credential provider named in config.GRPC.TCPTLSProvider. It is the
+// platform-agnostic counterpart to setupTLSFromWindowsCertStore, and is used
+// for PKCS#11 tokens, macOS Keychain and Linux TPM2/tpm2-pkcs11 backed keys.
+//
+// The provider's certificate is served through a tlsCertCache rather than
+// baked into a static tls.Config, so a certificate rotated on the token or
+// in the CA bundle is picked up on the next periodic refresh, or
+// immediately via the TLSReload ttrpc call.
+func setupTLSFromCredentialProvider(ctx context.Context, config *srvconfig.Config) ([]grpc.ServerOption, error) {
+	if err := config.GRPC.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid grpc configuration: %w", err)
+	}
+
+	provider, err := newTLSCredentialProvider(ctx, &config.GRPC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize TLS credential provider %q: %w", config.GRPC.TCPTLSProvider, err)
+	}
+
+	caFile := config.GRPC.TCPTLSCAFile
+	cache, err := newTLSCertCache(ctx, config.GRPC.TCPTLSRefreshInterval, func(ctx context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		tlsCert, err := tlsCertificateFromProvider(ctx, provider)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to build TLS certificate from provider %q: %w", config.GRPC.TCPTLSProvider, err)
+		}
+
+		var pool *x509.CertPool
+		if caFile != "" {
+			pool, err = certPoolFromFile(caFile)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to load client CA pool: %w", err)
+			}
+		}
+
+		return &tlsCert, pool, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS configuration from provider %q: %w", config.GRPC.TCPTLSProvider, err)
+	}
+	setActiveTLSCertCache(cache)
+
+	log.G(ctx).Infof("Loaded TLS configuration from %q credential provider", config.GRPC.TCPTLSProvider)
+
+	tcpServerOpts := append([]grpc.ServerOption{grpc.Creds(credentials.NewTLS(cache.TLSConfig()))}, grpcServerOptionsForAuthz(&config.GRPC)...)
+	return tcpServerOpts, nil
+}
+
+// readPinSource reads a PIN/passphrase from a file, trimming surrounding
+// whitespace. It is shared by the credential providers that authenticate to
+// their backing store with a PIN (PKCS#11, TPM2).
+func readPinSource(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// certPoolFromFile reads a PEM bundle of CA certificates from disk into an
+// x509.CertPool.
+func certPoolFromFile(path string) (*x509.CertPool, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,248 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+
+#include <stdlib.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+
+static CFDictionaryRef newIdentityQuery(CFStringRef label, CFArrayRef searchList) {
+	const void *keys[] = {
+		kSecClass,
+		kSecAttrLabel,
+		kSecReturnRef,
+		kSecMatchLimit,
+		kSecMatchSearchList,
+	};
+	const void *values[] = {
+		kSecClassIdentity,
+		label,
+		kCFBooleanTrue,
+		kSecMatchLimitOne,
+		searchList,
+	};
+	// kSecMatchSearchList must be omitted, not nil, when no keychain was
+	// named, or SecItemCopyMatching rejects the query.
+	int count = searchList == NULL ? 4 : 5;
+	return CFDictionaryCreate(kCFAllocatorDefault, keys, values, count,
+		&kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+}
+*/
+import "C"
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"unsafe"
+
+	srvconfig "github.com/containerd/containerd/v2/cmd/containerd/server/config"
+)
+
+func init() {
+	RegisterTLSCredentialProvider("macos-keychain", newKeychainProvider)
+}
+
+// keychainProvider is a TLSCredentialProvider backed by an identity (a
+// certificate paired with its private key) held in the macOS Keychain,
+// looked up by label through the Security framework's SecItemCopyMatching.
+// The private key is never exported; signing is delegated to
+// SecKeyCreateSignature, so the key material never leaves the keychain.
+type keychainProvider struct {
+	label        string
+	keychainName string
+}
+
+// newKeychainProvider returns a provider for the identity labeled
+// config.TCPTLSKeychainLabel in the keychain named by
+// config.TCPTLSKeychainName, or the default keychain search list if unset.
+// The identity itself is looked up on each Signer and Leaf call.
+func newKeychainProvider(_ context.Context, config *srvconfig.GRPCConfig) (TLSCredentialProvider, error) {
+	if config.TCPTLSKeychainLabel == "" {
+		return nil, fmt.Errorf("TCPTLSKeychainLabel is required for the macos-keychain TLS provider")
+	}
+	return &keychainProvider{label: config.TCPTLSKeychainLabel, keychainName: config.TCPTLSKeychainName}, nil
+}
+
+func (p *keychainProvider) Signer(context.Context) (crypto.Signer, error) {
+	identity, leaf, err := p.findIdentity()
+	if err != nil {
+		return nil, err
+	}
+	defer C.CFRelease(C.CFTypeRef(identity))
+
+	var key C.SecKeyRef
+	if status := C.SecIdentityCopyPrivateKey(identity, &key); status != C.errSecSuccess {
+		return nil, fmt.Errorf("failed to copy private key for keychain identity %q: status %d", p.label, status)
+	}
+
+	return &keychainSigner{key: key, public: leaf.PublicKey}, nil
+}
+
+func (p *keychainProvider) Leaf(context.Context) (*x509.Certificate, error) {
+	identity, leaf, err := p.findIdentity()
+	if err != nil {
+		return nil, err
+	}
+	C.CFRelease(C.CFTypeRef(identity))
+
+	return leaf, nil
+}
+
+func (p *keychainProvider) Chain(context.Context) ([]*x509.Certificate, error) {
+	// The Security framework does not hand back the issuing intermediates
+	// alongside a SecIdentityRef; resolving them would mean walking
+	// SecTrustRef evaluation results instead. This provider does not do
+	// that, so no intermediates are presented, only the leaf certificate.
+	return nil, nil
+}
+
+// findIdentity looks up the keychain identity labeled p.label and returns
+// both the SecIdentityRef (owned by the caller; must be released) and its
+// parsed leaf certificate.
+func (p *keychainProvider) findIdentity() (C.SecIdentityRef, *x509.Certificate, error) {
+	clabel := C.CString(p.label)
+	defer C.free(unsafe.Pointer(clabel))
+	label := C.CFStringCreateWithCString(C.kCFAllocatorDefault, clabel, C.kCFStringEncodingUTF8)
+	defer C.CFRelease(C.CFTypeRef(label))
+
+	var searchList C.CFArrayRef
+	if p.keychainName != "" {
+		keychain, status := openKeychain(p.keychainName)
+		if status != C.errSecSuccess {
+			return 0, nil, fmt.Errorf("failed to open keychain %q: status %d", p.keychainName, status)
+		}
+		defer C.CFRelease(C.CFTypeRef(keychain))
+
+		list := C.CFArrayCreate(C.kCFAllocatorDefault, (*unsafe.Pointer)(unsafe.Pointer(&keychain)), 1, &C.kCFTypeArrayCallBacks)
+		defer C.CFRelease(C.CFTypeRef(list))
+		searchList = list
+	}
+
+	query := C.newIdentityQuery(label, searchList)
+	defer C.CFRelease(C.CFTypeRef(query))
+
+	var result C.CFTypeRef
+	if status := C.SecItemCopyMatching(query, &result); status != C.errSecSuccess {
+		return 0, nil, fmt.Errorf("failed to find keychain identity %q: status %d", p.label, status)
+	}
+	identity := C.SecIdentityRef(result)
+
+	var certRef C.SecCertificateRef
+	if status := C.SecIdentityCopyCertificate(identity, &certRef); status != C.errSecSuccess {
+		C.CFRelease(result)
+		return 0, nil, fmt.Errorf("failed to copy certificate for keychain identity %q: status %d", p.label, status)
+	}
+	defer C.CFRelease(C.CFTypeRef(certRef))
+
+	der := C.SecCertificateCopyData(certRef)
+	defer C.CFRelease(C.CFTypeRef(der))
+
+	leaf, err := x509.ParseCertificate(C.GoBytes(unsafe.Pointer(C.CFDataGetBytePtr(der)), C.int(C.CFDataGetLength(der))))
+	if err != nil {
+		C.CFRelease(result)
+		return 0, nil, fmt.Errorf("failed to parse certificate for keychain identity %q: %w", p.label, err)
+	}
+
+	return identity, leaf, nil
+}
+
+// keychainSigner implements crypto.Signer on top of a SecKeyRef, so the
+// private key stays in the keychain and every signature is produced by
+// SecKeyCreateSignature.
+type keychainSigner struct {
+	key    C.SecKeyRef
+	public crypto.PublicKey
+}
+
+func (s *keychainSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *keychainSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	algorithm, err := secKeyAlgorithmFor(s.public, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	data := C.CFDataCreate(C.kCFAllocatorDefault, (*C.UInt8)(unsafe.Pointer(&digest[0])), C.CFIndex(len(digest)))
+	defer C.CFRelease(C.CFTypeRef(data))
+
+	var cfErr C.CFErrorRef
+	signature := C.SecKeyCreateSignature(s.key, algorithm, data, &cfErr)
+	if signature == 0 {
+		defer C.CFRelease(C.CFTypeRef(cfErr))
+		return nil, fmt.Errorf("SecKeyCreateSignature failed for keychain key")
+	}
+	defer C.CFRelease(C.CFTypeRef(signature))
+
+	return C.GoBytes(unsafe.Pointer(C.CFDataGetBytePtr(signature)), C.int(C.CFDataGetLength(signature))), nil
+}
+
+// openKeychain opens the keychain at path, returning the owned SecKeychainRef
+// and the OSStatus from SecKeychainOpen.
+func openKeychain(path string) (C.SecKeychainRef, C.OSStatus) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	var keychain C.SecKeychainRef
+	status := C.SecKeychainOpen(cpath, &keychain)
+	return keychain, status
+}
+
+// secKeyAlgorithmFor picks the SecKeyAlgorithm matching pub's key type and
+// opts' hash, mirroring the crypto.Signer contract that Sign is called with
+// the already-computed digest rather than the original message.
+func secKeyAlgorithmFor(pub crypto.PublicKey, opts crypto.SignerOpts) (C.SecKeyAlgorithm, error) {
+	_, isRSA := pub.(*rsa.PublicKey)
+	_, isPSS := opts.(*rsa.PSSOptions)
+
+	switch opts.HashFunc() {
+	case crypto.SHA256:
+		if isRSA && isPSS {
+			return C.kSecKeyAlgorithmRSASignatureDigestPSSSHA256, nil
+		}
+		if isRSA {
+			return C.kSecKeyAlgorithmRSASignatureDigestPKCS1v15SHA256, nil
+		}
+		return C.kSecKeyAlgorithmECDSASignatureDigestX962SHA256, nil
+	case crypto.SHA384:
+		if isRSA && isPSS {
+			return C.kSecKeyAlgorithmRSASignatureDigestPSSSHA384, nil
+		}
+		if isRSA {
+			return C.kSecKeyAlgorithmRSASignatureDigestPKCS1v15SHA384, nil
+		}
+		return C.kSecKeyAlgorithmECDSASignatureDigestX962SHA384, nil
+	case crypto.SHA512:
+		if isRSA && isPSS {
+			return C.kSecKeyAlgorithmRSASignatureDigestPSSSHA512, nil
+		}
+		if isRSA {
+			return C.kSecKeyAlgorithmRSASignatureDigestPKCS1v15SHA512, nil
+		}
+		return C.kSecKeyAlgorithmECDSASignatureDigestX962SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported signature hash %v for keychain key", opts.HashFunc())
+	}
+}
@@ -0,0 +1,111 @@
+//go:build !windows
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/ThalesGroup/crypto11"
+	srvconfig "github.com/containerd/containerd/v2/cmd/containerd/server/config"
+)
+
+func init() {
+	RegisterTLSCredentialProvider("pkcs11", newPKCS11Provider)
+}
+
+// pkcs11Provider is a TLSCredentialProvider backed by a PKCS#11 token, e.g.
+// an HSM or a smartcard. The private key never leaves the token: all
+// signing operations are performed by the PKCS#11 module.
+type pkcs11Provider struct {
+	ctx   *crypto11.Context
+	label string
+	id    []byte
+}
+
+// newPKCS11Provider opens the PKCS#11 module at config.TCPTLSPKCS11ModulePath
+// and locates the key/certificate identified by config.TCPTLSPKCS11TokenLabel,
+// config.TCPTLSPKCS11KeyLabel and config.TCPTLSPKCS11Pin (or
+// config.TCPTLSPKCS11PinSource, a path read once at startup).
+func newPKCS11Provider(_ context.Context, config *srvconfig.GRPCConfig) (TLSCredentialProvider, error) {
+	pin, err := resolvePKCS11Pin(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve PKCS#11 pin: %w", err)
+	}
+
+	cryptoCtx, err := crypto11.Configure(&crypto11.Config{
+		Path:       config.TCPTLSPKCS11ModulePath,
+		TokenLabel: config.TCPTLSPKCS11TokenLabel,
+		Pin:        pin,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PKCS#11 module %q: %w", config.TCPTLSPKCS11ModulePath, err)
+	}
+
+	return &pkcs11Provider{
+		ctx:   cryptoCtx,
+		label: config.TCPTLSPKCS11KeyLabel,
+	}, nil
+}
+
+func (p *pkcs11Provider) Signer(context.Context) (crypto.Signer, error) {
+	signer, err := p.ctx.FindKeyPair(p.id, []byte(p.label))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find PKCS#11 key pair %q: %w", p.label, err)
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("no PKCS#11 key pair found for label %q", p.label)
+	}
+	return signer, nil
+}
+
+func (p *pkcs11Provider) Leaf(context.Context) (*x509.Certificate, error) {
+	cert, err := p.ctx.FindCertificate(p.id, []byte(p.label), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find PKCS#11 certificate %q: %w", p.label, err)
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("no PKCS#11 certificate found for label %q", p.label)
+	}
+	return cert, nil
+}
+
+func (p *pkcs11Provider) Chain(context.Context) ([]*x509.Certificate, error) {
+	// PKCS#11 tokens typically only hold the leaf certificate; any
+	// intermediates are expected to be distributed alongside containerd's
+	// configuration and are not sourced from the token.
+	return nil, nil
+}
+
+// resolvePKCS11Pin returns the PIN used to log in to the PKCS#11 token,
+// preferring a PIN read from config.TCPTLSPKCS11PinSource over an inline
+// config.TCPTLSPKCS11Pin so that the PIN need not be stored in plaintext next
+// to the rest of the configuration.
+func resolvePKCS11Pin(config *srvconfig.GRPCConfig) (string, error) {
+	if config.TCPTLSPKCS11PinSource == "" {
+		return config.TCPTLSPKCS11Pin, nil
+	}
+	pin, err := readPinSource(config.TCPTLSPKCS11PinSource)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pin source %q: %w", config.TCPTLSPKCS11PinSource, err)
+	}
+	return pin, nil
+}
@@ -0,0 +1,94 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+
+	srvconfig "github.com/containerd/containerd/v2/cmd/containerd/server/config"
+	"golang.org/x/sys/windows"
+)
+
+func init() {
+	RegisterTLSCredentialProvider("windows-certstore", newWindowsCertStoreProvider)
+}
+
+// windowsCertStoreProvider is the TLSCredentialProvider form of
+// setupTLSFromWindowsCertStore, letting the Windows Certificate Store be
+// selected explicitly via `grpc.tcp_tls_provider: windows-certstore` alongside
+// the other providers registered in this package.
+//
+// Unlike the other providers in this package, the store backing this one is
+// routinely rotated out from under containerd by GPO/autoenroll, so nothing
+// is cached at construction: Signer, Leaf and Chain each re-open the store
+// and look the certificate up fresh, exactly as setupTLSFromWindowsCertStore
+// does for its own tlsCertCache loader. This is what lets the periodic
+// refresh and the TLSReload ttrpc call actually pick up a renewed
+// certificate for this provider.
+type windowsCertStoreProvider struct {
+	config *srvconfig.GRPCConfig
+}
+
+func newWindowsCertStoreProvider(_ context.Context, config *srvconfig.GRPCConfig) (TLSCredentialProvider, error) {
+	return &windowsCertStoreProvider{config: config}, nil
+}
+
+func (p *windowsCertStoreProvider) Signer(ctx context.Context) (crypto.Signer, error) {
+	certContext, leaf, err := findWindowsCertificate(ctx, p.config)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CertFreeCertificateContext(certContext)
+
+	return windowsCertificateKey(ctx, leaf, certContext)
+}
+
+func (p *windowsCertStoreProvider) Leaf(ctx context.Context) (*x509.Certificate, error) {
+	certContext, leaf, err := findWindowsCertificate(ctx, p.config)
+	if err != nil {
+		return nil, err
+	}
+	windows.CertFreeCertificateContext(certContext)
+
+	return leaf, nil
+}
+
+func (p *windowsCertStoreProvider) Chain(ctx context.Context) ([]*x509.Certificate, error) {
+	certContext, _, err := findWindowsCertificate(ctx, p.config)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CertFreeCertificateContext(certContext)
+
+	_, chainBytes, err := windowsCertificateChain(ctx, certContext)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := make([]*x509.Certificate, 0, len(chainBytes))
+	for _, der := range chainBytes {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate from chain: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+	return chain, nil
+}
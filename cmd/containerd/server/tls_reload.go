@@ -0,0 +1,139 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/containerd/log"
+)
+
+// defaultTLSRefreshInterval is used when the configuration does not specify
+// how often the TCP gRPC endpoint's TLS certificate should be re-read from
+// its backing store.
+const defaultTLSRefreshInterval = 10 * time.Minute
+
+// tlsCertLoader loads, or reloads, the active TLS certificate and, for mTLS
+// configurations, the pool of client CAs to verify peers against. It is
+// implemented by the Windows Certificate Store lookup and by the
+// TLSCredentialProvider-backed providers.
+type tlsCertLoader func(ctx context.Context) (*tls.Certificate, *x509.CertPool, error)
+
+// tlsCertCache refreshes a tlsCertLoader's result on a timer and serves it
+// through a tls.Config's GetCertificate/GetConfigForClient hooks, so a
+// rotated certificate (e.g. renewed via GPO autoenroll, or reissued by an
+// HSM) is picked up without restarting containerd. Reload can also be
+// triggered immediately, which is what the TLSReload admin RPC does.
+type tlsCertCache struct {
+	load tlsCertLoader
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// newTLSCertCache loads the initial certificate with load, then refreshes it
+// every interval (defaultTLSRefreshInterval if zero) until Close is called.
+func newTLSCertCache(ctx context.Context, interval time.Duration, load tlsCertLoader) (*tlsCertCache, error) {
+	c := &tlsCertCache{load: load, done: make(chan struct{})}
+	if err := c.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	if interval <= 0 {
+		interval = defaultTLSRefreshInterval
+	}
+	go c.refreshLoop(ctx, interval)
+
+	return c, nil
+}
+
+func (c *tlsCertCache) refreshLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Reload(ctx); err != nil {
+				log.G(ctx).WithError(err).Error("failed to refresh TLS certificate")
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Reload re-invokes the loader and swaps in its result.
+func (c *tlsCertCache) Reload(ctx context.Context) error {
+	cert, clientCA, err := c.load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cert = cert
+	c.clientCA = clientCA
+	c.mu.Unlock()
+
+	log.G(ctx).Info("reloaded TLS certificate for TCP gRPC endpoint")
+	return nil
+}
+
+// Close stops the background refresh loop. It does not affect the last
+// loaded certificate, which remains available through TLSConfig.
+func (c *tlsCertCache) Close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+func (c *tlsCertCache) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.cert == nil {
+		return nil, fmt.Errorf("no TLS certificate loaded")
+	}
+	return c.cert, nil
+}
+
+// TLSConfig returns a tls.Config that always serves the cache's current
+// certificate and client CA pool, re-evaluated on every handshake.
+func (c *tlsCertCache) TLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		GetCertificate: c.getCertificate,
+	}
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c.mu.RLock()
+		clientCA := c.clientCA
+		c.mu.RUnlock()
+
+		clone := cfg.Clone()
+		if clientCA != nil {
+			clone.ClientCAs = clientCA
+			clone.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		return clone, nil
+	}
+	return cfg
+}
@@ -0,0 +1,83 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/containerd/log"
+	"github.com/containerd/ttrpc"
+)
+
+var (
+	activeTLSCertCacheMu sync.Mutex
+	activeTLSCertCache   *tlsCertCache
+)
+
+// setActiveTLSCertCache records the cache backing the TCP gRPC endpoint's
+// TLS credentials, so the TLSReload ttrpc call has something to reload.
+func setActiveTLSCertCache(c *tlsCertCache) {
+	activeTLSCertCacheMu.Lock()
+	defer activeTLSCertCacheMu.Unlock()
+	activeTLSCertCache = c
+}
+
+// TLSReloadRequest is the (empty) request for the TLSReload ttrpc call.
+type TLSReloadRequest struct{}
+
+// Marshal and Unmarshal make TLSReloadRequest usable directly with ttrpc's
+// default codec without requiring generated protobuf bindings.
+func (*TLSReloadRequest) Marshal() ([]byte, error) { return nil, nil }
+func (*TLSReloadRequest) Unmarshal([]byte) error   { return nil }
+
+// TLSReloadResponse is the (empty) response for the TLSReload ttrpc call.
+type TLSReloadResponse struct{}
+
+func (*TLSReloadResponse) Marshal() ([]byte, error) { return nil, nil }
+func (*TLSReloadResponse) Unmarshal([]byte) error   { return nil }
+
+// registerTLSReloadService registers the admin "TLSReload" ttrpc call,
+// which forces the TCP gRPC endpoint's TLS certificate and client CA pool
+// to be re-read immediately instead of waiting for the next periodic
+// refresh. This is useful right after a Windows cert-store autoenroll
+// renewal, or after rotating keys on an HSM.
+func registerTLSReloadService(srv *ttrpc.Server) {
+	srv.Register("containerd.v1.TLSReload", map[string]ttrpc.Method{
+		"Reload": func(ctx context.Context, unmarshal func(interface{}) error) (interface{}, error) {
+			var req TLSReloadRequest
+			if err := unmarshal(&req); err != nil {
+				return nil, err
+			}
+
+			activeTLSCertCacheMu.Lock()
+			cache := activeTLSCertCache
+			activeTLSCertCacheMu.Unlock()
+			if cache == nil {
+				return nil, fmt.Errorf("TLS credentials for the TCP gRPC endpoint are not configured for hot-reload")
+			}
+
+			if err := cache.Reload(ctx); err != nil {
+				return nil, err
+			}
+
+			log.G(ctx).Info("TLS credentials reloaded via TLSReload RPC")
+			return &TLSReloadResponse{}, nil
+		},
+	})
+}
@@ -0,0 +1,138 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTLSCertCacheReload(t *testing.T) {
+	ctx := context.Background()
+
+	var gen int32
+	load := func(context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		n := atomic.AddInt32(&gen, 1)
+		return &tls.Certificate{Certificate: [][]byte{{byte(n)}}}, nil, nil
+	}
+
+	c, err := newTLSCertCache(ctx, time.Hour, load)
+	if err != nil {
+		t.Fatalf("newTLSCertCache() = %v, want nil", err)
+	}
+	defer c.Close()
+
+	cert, err := c.getCertificate(nil)
+	if err != nil {
+		t.Fatalf("getCertificate() = %v, want nil", err)
+	}
+	if got := cert.Certificate[0][0]; got != 1 {
+		t.Fatalf("initial certificate = %d, want 1", got)
+	}
+
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("Reload() = %v, want nil", err)
+	}
+
+	cert, err = c.getCertificate(nil)
+	if err != nil {
+		t.Fatalf("getCertificate() = %v, want nil", err)
+	}
+	if got := cert.Certificate[0][0]; got != 2 {
+		t.Fatalf("certificate after Reload = %d, want 2", got)
+	}
+}
+
+func TestTLSCertCacheReloadError(t *testing.T) {
+	ctx := context.Background()
+	loadErr := errors.New("boom")
+
+	c, err := newTLSCertCache(ctx, time.Hour, func(context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		return &tls.Certificate{}, nil, nil
+	})
+	if err != nil {
+		t.Fatalf("newTLSCertCache() = %v, want nil", err)
+	}
+	defer c.Close()
+
+	c.load = func(context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		return nil, nil, loadErr
+	}
+	if err := c.Reload(ctx); !errors.Is(err, loadErr) {
+		t.Fatalf("Reload() = %v, want wrapped %v", err, loadErr)
+	}
+
+	// A failed Reload must not clobber the previously loaded certificate.
+	if _, err := c.getCertificate(nil); err != nil {
+		t.Fatalf("getCertificate() after failed Reload = %v, want nil", err)
+	}
+}
+
+func TestTLSCertCacheGetCertificateBeforeLoad(t *testing.T) {
+	c := &tlsCertCache{done: make(chan struct{})}
+	if _, err := c.getCertificate(nil); err == nil {
+		t.Fatal("getCertificate() = nil, want error when no certificate has been loaded")
+	}
+}
+
+func TestTLSCertCacheTLSConfigAppliesClientCA(t *testing.T) {
+	ctx := context.Background()
+	pool := x509.NewCertPool()
+
+	c, err := newTLSCertCache(ctx, time.Hour, func(context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		return &tls.Certificate{}, pool, nil
+	})
+	if err != nil {
+		t.Fatalf("newTLSCertCache() = %v, want nil", err)
+	}
+	defer c.Close()
+
+	cfg, err := c.TLSConfig().GetConfigForClient(nil)
+	if err != nil {
+		t.Fatalf("GetConfigForClient() = %v, want nil", err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("ClientAuth = %v, want %v", cfg.ClientAuth, tls.RequireAndVerifyClientCert)
+	}
+	if cfg.ClientCAs != pool {
+		t.Fatal("ClientCAs was not propagated from the loaded client CA pool")
+	}
+}
+
+func TestTLSCertCacheClose(t *testing.T) {
+	ctx := context.Background()
+	c, err := newTLSCertCache(ctx, time.Millisecond, func(context.Context) (*tls.Certificate, *x509.CertPool, error) {
+		return &tls.Certificate{}, nil, nil
+	})
+	if err != nil {
+		t.Fatalf("newTLSCertCache() = %v, want nil", err)
+	}
+
+	c.Close()
+	c.Close() // must be safe to call twice
+
+	select {
+	case <-c.done:
+	default:
+		t.Fatal("Close() did not close the done channel")
+	}
+}